@@ -2,14 +2,24 @@ package auth
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"regexp"
+	"strings"
 )
 
 const (
-	// GitHubAPIURL is the base URL for GitHub's API.
+	// GitHubAPIURL is the base URL for GitHub.com's API.
 	GitHubAPIURL = "https://api.github.com"
+
+	// ghesAPIPath is the path suffix appended to a GitHub Enterprise Server
+	// hostname to reach its REST API, matching the convention used by the
+	// Dex GitHub connector.
+	ghesAPIPath = "/api/v3"
 )
 
 // GitHubUser represents a GitHub user.
@@ -28,23 +38,101 @@ type GitHubOrg struct {
 	Description string `json:"description"`
 }
 
+// GitHubTeam represents a GitHub team within an organization.
+type GitHubTeam struct {
+	Slug         string    `json:"slug"`
+	Name         string    `json:"name"`
+	Organization GitHubOrg `json:"organization"`
+}
+
+// linkNextRegexp matches the "next" relation in a GitHub Link header, e.g.:
+// <https://api.github.com/user/teams?page=2>; rel="next"
+var linkNextRegexp = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// Options configures a GitHubClient, letting callers point it at GitHub.com,
+// a GitHub Enterprise Server instance, or a custom HTTP client/CA trust store.
+type Options struct {
+	// BaseURL is the GitHub REST API base URL. Defaults to GitHubAPIURL.
+	BaseURL string
+
+	// HTTPClient is the HTTP client used for requests. Defaults to a plain
+	// http.Client with the standard library's TLS settings.
+	HTTPClient *http.Client
+}
+
 // GitHubClient is a client for GitHub's API.
 type GitHubClient struct {
 	accessToken string
 	httpClient  *http.Client
+	baseURL     string
 }
 
-// NewGitHubClient creates a new GitHub API client.
-func NewGitHubClient(accessToken string) *GitHubClient {
+// NewGitHubClient creates a new GitHub API client from the given options.
+// A zero-value Options targets GitHub.com with the default HTTP client.
+func NewGitHubClient(accessToken string, opts Options) *GitHubClient {
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = GitHubAPIURL
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
 	return &GitHubClient{
 		accessToken: accessToken,
-		httpClient:  &http.Client{},
+		httpClient:  httpClient,
+		baseURL:     baseURL,
+	}
+}
+
+// BaseURLForHost computes the GitHub REST API base URL for hostname, which
+// may be empty (GitHub.com) or a GitHub Enterprise Server hostname. GHES
+// instances serve their API under "/api/v3", matching the Dex GitHub
+// connector's convention. hostname must not contain a "/", which would allow
+// smuggling a different host or path into the resulting URL.
+func BaseURLForHost(hostname string) (string, error) {
+	if hostname == "" {
+		return GitHubAPIURL, nil
+	}
+
+	if strings.Contains(hostname, "/") {
+		return "", fmt.Errorf("invalid GitHub hostname %q: must not contain \"/\"", hostname)
+	}
+
+	return "https://" + hostname + ghesAPIPath, nil
+}
+
+// NewTLSClientForCA builds an *http.Client that trusts only the CA bundle at
+// rootCAPath; per Go's RootCAs semantics it replaces, rather than adds to,
+// the system trust store. rootCAPath may be empty, in which case a plain
+// http.Client using the system trust store is returned.
+func NewTLSClientForCA(rootCAPath string) (*http.Client, error) {
+	if rootCAPath == "" {
+		return &http.Client{}, nil
+	}
+
+	pemBytes, err := os.ReadFile(rootCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read root CA bundle %q: %w", rootCAPath, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("failed to parse root CA bundle %q: no certificates found", rootCAPath)
 	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
 }
 
 // GetUser fetches the authenticated user's information.
 func (c *GitHubClient) GetUser(ctx context.Context) (*GitHubUser, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", GitHubAPIURL+"/user", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/user", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +160,7 @@ func (c *GitHubClient) GetUser(ctx context.Context) (*GitHubUser, error) {
 
 // GetOrgs fetches the authenticated user's organizations.
 func (c *GitHubClient) GetOrgs(ctx context.Context) ([]GitHubOrg, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", GitHubAPIURL+"/user/orgs", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/user/orgs", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -150,3 +238,145 @@ func (c *GitHubClient) GetOrgNames(ctx context.Context) ([]string, error) {
 	return names, nil
 }
 
+// GetTeams fetches all teams the authenticated user belongs to, following
+// pagination via the response's "Link" header until no "next" page remains.
+func (c *GitHubClient) GetTeams(ctx context.Context) ([]GitHubTeam, error) {
+	var teams []GitHubTeam
+
+	url := c.baseURL + "/user/teams"
+	for url != "" {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch teams: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+
+			return nil, fmt.Errorf("GitHub API error: %s", resp.Status)
+		}
+
+		var page []GitHubTeam
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			resp.Body.Close()
+
+			return nil, fmt.Errorf("failed to parse teams response: %w", err)
+		}
+
+		teams = append(teams, page...)
+
+		url = nextPageURL(resp.Header.Get("Link"))
+
+		resp.Body.Close()
+	}
+
+	return teams, nil
+}
+
+// nextPageURL extracts the "next" page URL from a GitHub "Link" header, or
+// returns an empty string if there is no further page.
+func nextPageURL(linkHeader string) string {
+	if linkHeader == "" {
+		return ""
+	}
+
+	match := linkNextRegexp.FindStringSubmatch(linkHeader)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+// GetTeamsByOrg fetches the authenticated user's teams and aggregates them
+// into a map of organization login -> team slugs within that organization.
+func (c *GitHubClient) GetTeamsByOrg(ctx context.Context) (map[string][]string, error) {
+	teams, err := c.GetTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byOrg := make(map[string][]string)
+	for _, team := range teams {
+		org := team.Organization.Login
+		byOrg[org] = append(byOrg[org], team.Slug)
+	}
+
+	return byOrg, nil
+}
+
+// repoCollaboratorPermission is the response body of the
+// /repos/{owner}/{repo}/collaborators/{user}/permission endpoint.
+type repoCollaboratorPermission struct {
+	Permission string `json:"permission"`
+}
+
+// GetRepoPermission returns username's permission level on owner/repo, one
+// of "admin", "maintain", "write", "triage", "read", or "none".
+func (c *GitHubClient) GetRepoPermission(ctx context.Context, owner, repo, username string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/collaborators/%s/permission", c.baseURL, owner, repo, username)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch repo permission: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub API error: %s", resp.Status)
+	}
+
+	var perm repoCollaboratorPermission
+	if err := json.NewDecoder(resp.Body).Decode(&perm); err != nil {
+		return "", fmt.Errorf("failed to parse repo permission response: %w", err)
+	}
+
+	return perm.Permission, nil
+}
+
+// IsOrgMember checks whether the given user is a member of org, using the
+// `/orgs/{org}/members/{user}` endpoint. This succeeds even when the user's
+// organization membership is private, provided the token has `read:org` scope.
+func (c *GitHubClient) IsOrgMember(ctx context.Context, org, username string) (bool, error) {
+	url := fmt.Sprintf("%s/orgs/%s/members/%s", c.baseURL, org, username)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to check org membership: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("GitHub API error: %s", resp.Status)
+	}
+}
+
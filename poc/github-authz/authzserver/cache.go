@@ -0,0 +1,93 @@
+package authzserver
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a single LRU cache entry. A non-nil err represents a cached
+// failure (negative cache) and value is nil in that case.
+type cacheEntry struct {
+	key       string
+	value     interface{}
+	err       error
+	expiresAt time.Time
+}
+
+// lruCache is a fixed-capacity, least-recently-used cache of cacheEntry
+// values, used both for validated identities and for repo permission
+// lookups. Callers that key entries by a bearer token must hash it first
+// (see hashToken) so a heap dump cannot leak live credentials.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+// newLRUCache creates an lruCache holding at most capacity entries.
+func newLRUCache(capacity int) *lruCache {
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	return &lruCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the entry for key if present and not expired.
+func (c *lruCache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry, _ := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+
+	return entry, true
+}
+
+// set inserts or updates entry, evicting the least-recently-used entry if
+// the cache is over capacity.
+func (c *lruCache) set(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[entry.key]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+
+		return
+	}
+
+	c.items[entry.key] = c.ll.PushFront(entry)
+
+	for c.ll.Len() > c.capacity {
+		if oldest := c.ll.Back(); oldest != nil {
+			c.removeElement(oldest)
+		}
+	}
+}
+
+// removeElement removes el from both the list and the index. Callers must
+// hold c.mu.
+func (c *lruCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+
+	if entry, ok := el.Value.(*cacheEntry); ok {
+		delete(c.items, entry.key)
+	}
+}
@@ -0,0 +1,81 @@
+package authzserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheGetSet(t *testing.T) {
+	c := newLRUCache(10)
+
+	if _, ok := c.get("missing"); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+
+	c.set(&cacheEntry{key: "a", value: "1", expiresAt: time.Now().Add(time.Minute)})
+
+	entry, ok := c.get("a")
+	if !ok {
+		t.Fatal("expected a hit after set")
+	}
+
+	if entry.value.(string) != "1" {
+		t.Fatalf("got value %v, want %q", entry.value, "1")
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	c := newLRUCache(10)
+
+	c.set(&cacheEntry{key: "a", value: "1", expiresAt: time.Now().Add(-time.Second)})
+
+	if _, ok := c.get("a"); ok {
+		t.Fatal("expected expired entry to be evicted on read")
+	}
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	c := newLRUCache(2)
+
+	c.set(&cacheEntry{key: "a", value: "1", expiresAt: time.Now().Add(time.Minute)})
+	c.set(&cacheEntry{key: "b", value: "2", expiresAt: time.Now().Add(time.Minute)})
+
+	// Touch "a" so "b" becomes the least-recently-used entry.
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("expected a hit for \"a\"")
+	}
+
+	c.set(&cacheEntry{key: "c", value: "3", expiresAt: time.Now().Add(time.Minute)})
+
+	if _, ok := c.get("b"); ok {
+		t.Fatal("expected \"b\" to have been evicted as least-recently-used")
+	}
+
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("expected \"a\" to survive eviction")
+	}
+
+	if _, ok := c.get("c"); !ok {
+		t.Fatal("expected \"c\" to have been inserted")
+	}
+}
+
+func TestLRUCacheNegativeEntry(t *testing.T) {
+	c := newLRUCache(10)
+
+	wantErr := errTest{"boom"}
+	c.set(&cacheEntry{key: "a", err: wantErr, expiresAt: time.Now().Add(time.Minute)})
+
+	entry, ok := c.get("a")
+	if !ok {
+		t.Fatal("expected a hit for a negative entry")
+	}
+
+	if entry.err != wantErr {
+		t.Fatalf("got err %v, want %v", entry.err, wantErr)
+	}
+}
+
+type errTest struct{ msg string }
+
+func (e errTest) Error() string { return e.msg }
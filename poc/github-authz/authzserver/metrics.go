@@ -0,0 +1,28 @@
+package authzserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cacheHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "github_authz_cache_hits_total",
+		Help: "Number of token validations, org membership and repo permission lookups served from the positive cache.",
+	})
+
+	cacheMissesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "github_authz_cache_misses_total",
+		Help: "Number of token validations, org membership and repo permission lookups that missed the cache entirely.",
+	})
+
+	cacheNegativeHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "github_authz_cache_negative_hits_total",
+		Help: "Number of token validations, org membership and repo permission lookups served from the negative (failure) cache.",
+	})
+
+	singleflightCollapsesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "github_authz_singleflight_collapses_total",
+		Help: "Number of concurrent token validations, org membership or repo permission lookups for the same key collapsed into one upstream call.",
+	})
+)
@@ -0,0 +1,218 @@
+package authzserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/agntcy/dir-staging/poc/github-authz/auth"
+)
+
+// repoPermRank orders GitHub repo permission levels from weakest to
+// strongest, so a granted level can be compared against a required minimum.
+var repoPermRank = map[string]int{
+	"none":     0,
+	"read":     1,
+	"triage":   2,
+	"write":    3,
+	"maintain": 4,
+	"admin":    5,
+}
+
+// meetsMinRepoPerm reports whether have satisfies a minimum requirement of
+// want. An empty want is always satisfied.
+func meetsMinRepoPerm(have, want string) bool {
+	if want == "" {
+		return true
+	}
+
+	haveRank, haveOK := repoPermRank[strings.ToLower(have)]
+	wantRank, wantOK := repoPermRank[strings.ToLower(want)]
+
+	return haveOK && wantOK && haveRank >= wantRank
+}
+
+// Rule is a per-request authorization rule matched against the proxied
+// request's path and method. Rules are evaluated in order; the first
+// matching rule is enforced and later rules are not considered.
+type Rule struct {
+	// PathPrefix restricts the rule to paths with this prefix. Empty matches any path.
+	PathPrefix string
+
+	// Method restricts the rule to this HTTP method (case-insensitive). Empty matches any method.
+	Method string
+
+	// RequireOrgs, if non-empty, requires membership in at least one of these organizations.
+	RequireOrgs []string
+
+	// RequireTeams, if non-empty, requires team membership: org -> allowed team slugs.
+	RequireTeams map[string][]string
+
+	// RequireRepoPerm, if non-empty, requires at least this permission level
+	// on at least one repo in Repos (or, if Repos is empty, Config.RepoAllowList).
+	RequireRepoPerm string
+
+	// Repos, if non-empty, overrides Config.RepoAllowList as the set of
+	// repos RequireRepoPerm is checked against, letting different rules
+	// target different upstream repos.
+	Repos []string
+}
+
+// matches reports whether rule applies to a request with the given path and method.
+func (r Rule) matches(path, method string) bool {
+	if r.PathPrefix != "" && !strings.HasPrefix(path, r.PathPrefix) {
+		return false
+	}
+
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+
+	return true
+}
+
+// checkRules finds the first Rule matching path/method and enforces it
+// against identity. A request that matches no rule is allowed by this layer
+// (rules are an additional restriction on top of the org/team/user checks
+// in checkAuthorization, not a replacement for them).
+func (s *AuthorizationServer) checkRules(ctx context.Context, token string, identity *Identity, path, method string) error {
+	// Snapshot the config once so a concurrent policy reload can't apply
+	// part of the old policy and part of the new one to a single request.
+	cfg := s.cfg()
+
+	for _, rule := range cfg.Rules {
+		if !rule.matches(path, method) {
+			continue
+		}
+
+		if len(rule.RequireOrgs) > 0 && !identityInAnyOrg(identity, rule.RequireOrgs) {
+			return fmt.Errorf("user %q is not a member of any organization required by rule %q", identity.Username, rule.PathPrefix)
+		}
+
+		for org, requiredTeams := range rule.RequireTeams {
+			if !hasAnyTeamSlug(identity.Teams[org], requiredTeams) {
+				return fmt.Errorf("user %q is not a member of any team in %q required by rule %q", identity.Username, org, rule.PathPrefix)
+			}
+		}
+
+		repos := rule.Repos
+		if len(repos) == 0 {
+			repos = cfg.RepoAllowList
+		}
+
+		if err := s.checkRepoPermissions(ctx, token, identity, repos, rule.RequireRepoPerm); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// identityInAnyOrg reports whether identity belongs to any org in orgs,
+// compared case-insensitively.
+func identityInAnyOrg(identity *Identity, orgs []string) bool {
+	for _, want := range orgs {
+		for _, have := range identity.Orgs {
+			if strings.EqualFold(have, want) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkRepoPermissions reports whether identity holds at least minPerm on
+// any repo in repos: like the other *AllowList fields in this package,
+// repos is an allow list, so a single sufficient permission grants access
+// rather than requiring minPerm on every repo simultaneously. It is a no-op
+// when repos or minPerm are empty.
+func (s *AuthorizationServer) checkRepoPermissions(ctx context.Context, token string, identity *Identity, repos []string, minPerm string) error {
+	if minPerm == "" || len(repos) == 0 {
+		return nil
+	}
+
+	if identity.AuthMethod != "github-oauth" {
+		return fmt.Errorf("repo permission checks require a GitHub-authenticated identity")
+	}
+
+	var lastErr error
+
+	for _, repo := range repos {
+		owner, name, ok := strings.Cut(repo, "/")
+		if !ok {
+			return fmt.Errorf("invalid repo %q in allow list: expected \"owner/repo\"", repo)
+		}
+
+		perm, err := s.repoPermission(ctx, token, identity.Username, owner, name)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to check permission on %q: %w", repo, err)
+
+			continue
+		}
+
+		if meetsMinRepoPerm(perm, minPerm) {
+			return nil
+		}
+	}
+
+	if lastErr != nil {
+		return lastErr
+	}
+
+	return fmt.Errorf("user %q does not have %q permission on any repo in %v", identity.Username, minPerm, repos)
+}
+
+// repoPermission returns username's permission level on owner/repo, caching
+// the result (positive or negative) and collapsing concurrent lookups for
+// the same token and repo into one upstream round trip, same as
+// isOrgMember.
+func (s *AuthorizationServer) repoPermission(ctx context.Context, token, username, owner, repo string) (string, error) {
+	key := hashToken(token) + ":" + owner + "/" + repo
+
+	if entry, ok := s.repoPermCache.get(key); ok {
+		if entry.err != nil {
+			cacheNegativeHitsTotal.Inc()
+			return "", entry.err
+		}
+
+		cacheHitsTotal.Inc()
+
+		perm, _ := entry.value.(string)
+
+		return perm, nil
+	}
+
+	cacheMissesTotal.Inc()
+
+	result, err, shared := s.sf.Do(key, func() (interface{}, error) {
+		perm, verr := auth.NewGitHubClient(token, s.githubOpts).GetRepoPermission(ctx, owner, repo, username)
+
+		ttl := s.userCacheTTL
+		if verr != nil {
+			ttl = s.negativeCacheTTL
+		}
+
+		s.repoPermCache.set(&cacheEntry{
+			key:       key,
+			value:     perm,
+			err:       verr,
+			expiresAt: time.Now().Add(ttl),
+		})
+
+		return perm, verr
+	})
+	if shared {
+		singleflightCollapsesTotal.Inc()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	perm, _ := result.(string)
+
+	return perm, nil
+}
@@ -0,0 +1,164 @@
+package authzserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agntcy/dir-staging/poc/github-authz/auth"
+)
+
+func TestMeetsMinRepoPerm(t *testing.T) {
+	tests := []struct {
+		have, want string
+		ok         bool
+	}{
+		{"admin", "write", true},
+		{"write", "write", true},
+		{"read", "write", false},
+		{"write", "", true},
+		{"bogus", "write", false},
+		{"write", "bogus", false},
+	}
+
+	for _, tt := range tests {
+		if got := meetsMinRepoPerm(tt.have, tt.want); got != tt.ok {
+			t.Errorf("meetsMinRepoPerm(%q, %q) = %v, want %v", tt.have, tt.want, got, tt.ok)
+		}
+	}
+}
+
+// newRepoPermTestServer serves /repos/{owner}/{repo}/collaborators/{user}/permission,
+// returning perms[owner+"/"+repo] (or "none" if absent).
+func newRepoPermTestServer(t *testing.T, perms map[string]string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/", func(w http.ResponseWriter, r *http.Request) {
+		// Path shape: /repos/{owner}/{repo}/collaborators/{user}/permission
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/repos/"), "/")
+		if len(parts) != 5 || parts[2] != "collaborators" || parts[4] != "permission" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		owner, repo := parts[0], parts[1]
+
+		perm, ok := perms[owner+"/"+repo]
+		if !ok {
+			perm = "none"
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"permission": perm})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newTestServerWithGitHub(validator TokenValidator, githubOpts auth.Options) *AuthorizationServer {
+	s := newTestServer(validator, 0)
+	s.githubOpts = githubOpts
+
+	return s
+}
+
+func TestCheckRepoPermissionsAllowsOnAnyMatchingRepo(t *testing.T) {
+	server := newRepoPermTestServer(t, map[string]string{
+		"acme/api": "read",
+		"acme/web": "write",
+	})
+	defer server.Close()
+
+	s := newTestServerWithGitHub(nil, auth.Options{BaseURL: server.URL, HTTPClient: server.Client()})
+
+	identity := &Identity{Username: "octocat", AuthMethod: "github-oauth"}
+
+	err := s.checkRepoPermissions(context.Background(), "tok", identity, []string{"acme/api", "acme/web"}, "write")
+	if err != nil {
+		t.Fatalf("expected access via acme/web (write), got error: %v", err)
+	}
+}
+
+func TestCheckRepoPermissionsDeniesWhenNoRepoQualifies(t *testing.T) {
+	server := newRepoPermTestServer(t, map[string]string{
+		"acme/api": "read",
+		"acme/web": "read",
+	})
+	defer server.Close()
+
+	s := newTestServerWithGitHub(nil, auth.Options{BaseURL: server.URL, HTTPClient: server.Client()})
+
+	identity := &Identity{Username: "octocat", AuthMethod: "github-oauth"}
+
+	err := s.checkRepoPermissions(context.Background(), "tok", identity, []string{"acme/api", "acme/web"}, "write")
+	if err == nil {
+		t.Fatal("expected an error since neither repo grants write")
+	}
+}
+
+func TestCheckRepoPermissionsNoOpWithoutConfig(t *testing.T) {
+	s := newTestServerWithGitHub(nil, auth.Options{})
+	identity := &Identity{Username: "octocat", AuthMethod: "github-oauth"}
+
+	if err := s.checkRepoPermissions(context.Background(), "tok", identity, nil, "write"); err != nil {
+		t.Fatalf("expected no-op with empty repo list, got: %v", err)
+	}
+
+	if err := s.checkRepoPermissions(context.Background(), "tok", identity, []string{"acme/api"}, ""); err != nil {
+		t.Fatalf("expected no-op with empty minPerm, got: %v", err)
+	}
+}
+
+func TestCheckRepoPermissionsRejectsNonGitHubIdentity(t *testing.T) {
+	s := newTestServerWithGitHub(nil, auth.Options{})
+	identity := &Identity{Username: "octocat", AuthMethod: "oidc"}
+
+	err := s.checkRepoPermissions(context.Background(), "tok", identity, []string{"acme/api"}, "write")
+	if err == nil {
+		t.Fatal("expected an error for a non-GitHub identity")
+	}
+}
+
+func TestCheckRulesUsesPerRuleRepos(t *testing.T) {
+	server := newRepoPermTestServer(t, map[string]string{
+		"acme/internal-api": "write",
+	})
+	defer server.Close()
+
+	s := newTestServerWithGitHub(nil, auth.Options{BaseURL: server.URL, HTTPClient: server.Client()})
+	s.config.Store(&Config{
+		RepoAllowList: []string{"acme/unrelated-repo"},
+		Rules: []Rule{
+			{
+				PathPrefix:      "/internal/",
+				RequireRepoPerm: "write",
+				Repos:           []string{"acme/internal-api"},
+			},
+		},
+	})
+
+	identity := &Identity{Username: "octocat", AuthMethod: "github-oauth"}
+
+	if err := s.checkRules(context.Background(), "tok", identity, "/internal/widgets", "GET"); err != nil {
+		t.Fatalf("expected the rule's own Repos to be checked instead of the global RepoAllowList: %v", err)
+	}
+}
+
+func TestCheckRulesNoMatchingRuleAllows(t *testing.T) {
+	s := newTestServerWithGitHub(nil, auth.Options{})
+	s.config.Store(&Config{
+		Rules: []Rule{
+			{PathPrefix: "/admin/", RequireOrgs: []string{"acme"}},
+		},
+	})
+
+	identity := &Identity{Username: "octocat", AuthMethod: "github-oauth"}
+
+	if err := s.checkRules(context.Background(), "tok", identity, "/public/widgets", "GET"); err != nil {
+		t.Fatalf("expected no matching rule to allow the request, got: %v", err)
+	}
+}
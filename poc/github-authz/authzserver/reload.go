@@ -0,0 +1,177 @@
+package authzserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// policyFile is the YAML schema for a hot-reloadable authorization policy.
+// Fields not covered here (cache sizes, GitHub/OIDC connection settings) are
+// left untouched on reload.
+type policyFile struct {
+	Organizations []string            `yaml:"organizations"`
+	Teams         map[string][]string `yaml:"teams"`
+	UsersAllow    []string            `yaml:"users_allow"`
+	UsersDeny     []string            `yaml:"users_deny"`
+	Rules         []policyFileRule    `yaml:"rules"`
+}
+
+// policyFileRule is the YAML representation of a Rule.
+type policyFileRule struct {
+	PathPrefix      string              `yaml:"path_prefix"`
+	Method          string              `yaml:"method"`
+	RequireOrgs     []string            `yaml:"require_orgs"`
+	RequireTeams    map[string][]string `yaml:"require_teams"`
+	RequireRepoPerm string              `yaml:"require_repo_perm"`
+	Repos           []string            `yaml:"repos"`
+}
+
+// loadPolicyFile parses and validates the YAML policy document at path, and
+// returns a copy of base with the organization/team/user allow lists and
+// Rules replaced by its contents.
+func loadPolicyFile(path string, base *Config) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file %q: %w", path, err)
+	}
+
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %q: %w", path, err)
+	}
+
+	rules := make([]Rule, 0, len(file.Rules))
+	for i, r := range file.Rules {
+		if r.RequireRepoPerm != "" {
+			if _, ok := repoPermRank[r.RequireRepoPerm]; !ok {
+				return nil, fmt.Errorf("policy file %q: rules[%d].require_repo_perm: unknown permission %q", path, i, r.RequireRepoPerm)
+			}
+		}
+
+		rules = append(rules, Rule{
+			PathPrefix:      r.PathPrefix,
+			Method:          r.Method,
+			RequireOrgs:     r.RequireOrgs,
+			RequireTeams:    r.RequireTeams,
+			RequireRepoPerm: r.RequireRepoPerm,
+			Repos:           r.Repos,
+		})
+	}
+
+	updated := *base
+	updated.OrganizationAllowList = file.Organizations
+	updated.TeamAllowList = file.Teams
+	updated.UserAllowList = file.UsersAllow
+	updated.UserDenyList = file.UsersDeny
+	updated.Rules = rules
+
+	return &updated, nil
+}
+
+// WatchPolicyFile watches config.PolicyFilePath for changes and atomically
+// swaps in a freshly loaded Config on every write, logging what changed. A
+// reload that fails to parse or validate is rejected and the existing
+// policy is kept. It blocks until ctx is done, so callers typically run it
+// in a goroutine. A server with no PolicyFilePath configured returns
+// immediately.
+func (s *AuthorizationServer) WatchPolicyFile(ctx context.Context) error {
+	path := s.cfg().PolicyFilePath
+	if path == "" {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start policy file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the containing directory rather than the file itself: editors
+	// and config-management tools commonly replace a file via rename, which
+	// would otherwise orphan a watch on the original inode.
+	dir := filepath.Dir(path)
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %q: %w", dir, err)
+	}
+
+	s.logger.Info("watching policy file for changes", "path", path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if filepath.Clean(event.Name) != filepath.Clean(path) {
+				continue
+			}
+
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			s.reloadPolicyFile(path)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			s.logger.Error("policy file watcher error", "error", err)
+		}
+	}
+}
+
+// reloadPolicyFile reloads and validates the policy file at path, swapping
+// it in and logging a diff on success. On failure the existing policy is
+// left in place.
+func (s *AuthorizationServer) reloadPolicyFile(path string) {
+	oldConfig := s.cfg()
+
+	newConfig, err := loadPolicyFile(path, oldConfig)
+	if err != nil {
+		s.logger.Error("rejected policy file reload, keeping previous policy", "path", path, "error", err)
+
+		return
+	}
+
+	s.config.Store(newConfig)
+
+	s.logger.Info("reloaded policy file", append([]any{"path", path}, policyDiff(oldConfig, newConfig)...)...)
+}
+
+// policyDiff returns structured slog key/value pairs for the fields that
+// differ between oldConfig and newConfig.
+func policyDiff(oldConfig, newConfig *Config) []any {
+	var attrs []any
+
+	if !reflect.DeepEqual(oldConfig.OrganizationAllowList, newConfig.OrganizationAllowList) {
+		attrs = append(attrs, "organizations_before", oldConfig.OrganizationAllowList, "organizations_after", newConfig.OrganizationAllowList)
+	}
+
+	if !reflect.DeepEqual(oldConfig.TeamAllowList, newConfig.TeamAllowList) {
+		attrs = append(attrs, "teams_before", oldConfig.TeamAllowList, "teams_after", newConfig.TeamAllowList)
+	}
+
+	if !reflect.DeepEqual(oldConfig.UserAllowList, newConfig.UserAllowList) {
+		attrs = append(attrs, "users_allow_before", oldConfig.UserAllowList, "users_allow_after", newConfig.UserAllowList)
+	}
+
+	if !reflect.DeepEqual(oldConfig.UserDenyList, newConfig.UserDenyList) {
+		attrs = append(attrs, "users_deny_before", oldConfig.UserDenyList, "users_deny_after", newConfig.UserDenyList)
+	}
+
+	if !reflect.DeepEqual(oldConfig.Rules, newConfig.Rules) {
+		attrs = append(attrs, "rules_before_count", len(oldConfig.Rules), "rules_after_count", len(newConfig.Rules))
+	}
+
+	return attrs
+}
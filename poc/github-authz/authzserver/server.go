@@ -1,43 +1,66 @@
 // Package authzserver implements Envoy's External Authorization gRPC API
-// for validating GitHub OAuth tokens and enforcing authorization rules.
+// for validating GitHub OAuth or OIDC/JWT bearer tokens and enforcing
+// authorization rules.
 package authzserver
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/codes"
 
 	"github.com/agntcy/dir-staging/poc/github-authz/auth"
+	"github.com/agntcy/dir-staging/poc/github-authz/oidc"
 )
 
 // AuthorizationServer implements the Envoy ext_authz gRPC API.
 type AuthorizationServer struct {
 	authv3.UnimplementedAuthorizationServer
 
-	config *Config
+	// config is swapped atomically by WatchPolicyFile so Check can read it
+	// lock-free while a policy reload is in progress.
+	config atomic.Pointer[Config]
 	logger *slog.Logger
 
-	// Cache for GitHub API responses
-	userCache    map[string]*cachedUser
-	userCacheMu  sync.RWMutex
-	userCacheTTL time.Duration
-}
+	// githubOpts configures GitHubClients created for token validation and
+	// authorization checks, pointed at GitHub.com or a GHES instance.
+	githubOpts auth.Options
+
+	// githubValidator handles opaque GitHub OAuth/PAT bearer tokens.
+	githubValidator TokenValidator
+
+	// oidcValidator handles JWT bearer tokens, e.g. SPIFFE JWT-SVIDs or
+	// Dex-issued ID tokens. Nil if no OIDC issuer is configured.
+	oidcValidator TokenValidator
+
+	// cache holds validated (and failed) identities, keyed by sha256(token)
+	// so raw bearer tokens are never retained in memory.
+	cache            *lruCache
+	userCacheTTL     time.Duration
+	negativeCacheTTL time.Duration
+
+	// repoPermCache holds GitHub repo collaborator permission lookups, keyed
+	// by sha256(token) plus "owner/repo".
+	repoPermCache *lruCache
+
+	// orgMemberCache holds GitHub private-membership fallback lookups
+	// (IsOrgMember), keyed by sha256(token) plus the org login.
+	orgMemberCache *lruCache
 
-// cachedUser stores cached GitHub user information.
-type cachedUser struct {
-	user      *auth.GitHubUser
-	orgs      []string
-	teams     map[string][]string // org -> teams
-	expiresAt time.Time
+	// sf collapses concurrent validations of the same token into one
+	// upstream round trip.
+	sf singleflight.Group
 }
 
 // Config holds the authorization server configuration.
@@ -57,8 +80,63 @@ type Config struct {
 	// UserDenyList explicitly denies specific users (takes precedence over allow lists).
 	UserDenyList []string
 
-	// CacheTTL is how long to cache GitHub API responses.
+	// CacheTTL is how long to cache successful token validations.
 	CacheTTL time.Duration
+
+	// MaxCacheEntries bounds the number of validated (and negatively cached)
+	// identities held in memory at once, evicting least-recently-used
+	// entries beyond this size.
+	MaxCacheEntries int
+
+	// NegativeCacheTTL is how long a failed token validation is cached,
+	// protecting upstream APIs from repeated lookups of a bad token.
+	NegativeCacheTTL time.Duration
+
+	// GitHubHostName, if set, points the server at a GitHub Enterprise Server
+	// instance at this hostname (e.g. "github.example.com") instead of
+	// GitHub.com. The REST API is reached at https://<host>/api/v3.
+	GitHubHostName string
+
+	// RootCAPath, if set, is a PEM-encoded CA bundle used to validate the
+	// GitHub API's TLS certificate, for enterprise deployments behind a
+	// private PKI.
+	RootCAPath string
+
+	// OIDCIssuer, if set, enables JWT bearer token validation against this
+	// OIDC issuer, as an alternative to GitHub OAuth tokens.
+	OIDCIssuer string
+
+	// OIDCAudience is the expected "aud" claim on JWTs from OIDCIssuer.
+	OIDCAudience string
+
+	// OIDCGroupsClaim is the JWT claim used as the equivalent of GitHub
+	// organization membership, e.g. "groups" or "roles". Defaults to "groups".
+	OIDCGroupsClaim string
+
+	// RepoAllowList restricts the repos a RequiredRepoPermission (or a Rule's
+	// RequireRepoPerm) is checked against, as "owner/repo" strings.
+	RepoAllowList []string
+
+	// RequiredRepoPermission, if set, requires this minimum GitHub
+	// collaborator permission level ("read", "triage", "write", "maintain",
+	// or "admin") on at least one repo in RepoAllowList, for every request.
+	RequiredRepoPermission string
+
+	// Rules are additional per-request authorization rules matched against
+	// the proxied request's path and method, evaluated in order after the
+	// org/team/user checks above have passed.
+	Rules []Rule
+
+	// PolicyFilePath, if set, loads the organization/team/user allow lists
+	// and Rules from this YAML file instead of the fields above, and hot
+	// reloads them on change. See WatchPolicyFile.
+	PolicyFilePath string
+}
+
+// cfg returns the current Config, reflecting the latest policy reload if
+// WatchPolicyFile is running.
+func (s *AuthorizationServer) cfg() *Config {
+	return s.config.Load()
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -69,11 +147,15 @@ func DefaultConfig() *Config {
 		UserAllowList:         []string{},
 		UserDenyList:          []string{},
 		CacheTTL:              5 * time.Minute,
+		MaxCacheEntries:       10000,
+		NegativeCacheTTL:      30 * time.Second,
+		RepoAllowList:         []string{},
 	}
 }
 
-// NewAuthorizationServer creates a new authorization server.
-func NewAuthorizationServer(config *Config, logger *slog.Logger) *AuthorizationServer {
+// NewAuthorizationServer creates a new authorization server. It returns an
+// error if config.GitHubHostName or config.RootCAPath are invalid.
+func NewAuthorizationServer(config *Config, logger *slog.Logger) (*AuthorizationServer, error) {
 	if config == nil {
 		config = DefaultConfig()
 	}
@@ -83,13 +165,63 @@ func NewAuthorizationServer(config *Config, logger *slog.Logger) *AuthorizationS
 	if config.CacheTTL == 0 {
 		config.CacheTTL = 5 * time.Minute
 	}
+	if config.MaxCacheEntries == 0 {
+		config.MaxCacheEntries = 10000
+	}
+	if config.NegativeCacheTTL == 0 {
+		config.NegativeCacheTTL = 30 * time.Second
+	}
+
+	if config.PolicyFilePath != "" {
+		loaded, err := loadPolicyFile(config.PolicyFilePath, config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy file: %w", err)
+		}
+
+		config = loaded
+	}
+
+	baseURL, err := auth.BaseURLForHost(config.GitHubHostName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub host configuration: %w", err)
+	}
+
+	httpClient, err := auth.NewTLSClientForCA(config.RootCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub root CA configuration: %w", err)
+	}
+
+	githubOpts := auth.Options{
+		BaseURL:    baseURL,
+		HTTPClient: httpClient,
+	}
+
+	var oidcValidator TokenValidator
+	if config.OIDCIssuer != "" {
+		oidcValidator, err = NewOIDCTokenValidator(oidc.Config{
+			Issuer:      config.OIDCIssuer,
+			Audience:    config.OIDCAudience,
+			GroupsClaim: config.OIDCGroupsClaim,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid OIDC configuration: %w", err)
+		}
+	}
 
-	return &AuthorizationServer{
-		config:       config,
-		logger:       logger,
-		userCache:    make(map[string]*cachedUser),
-		userCacheTTL: config.CacheTTL,
+	srv := &AuthorizationServer{
+		logger:           logger,
+		githubOpts:       githubOpts,
+		githubValidator:  NewGitHubTokenValidator(githubOpts),
+		oidcValidator:    oidcValidator,
+		cache:            newLRUCache(config.MaxCacheEntries),
+		repoPermCache:    newLRUCache(config.MaxCacheEntries),
+		orgMemberCache:   newLRUCache(config.MaxCacheEntries),
+		userCacheTTL:     config.CacheTTL,
+		negativeCacheTTL: config.NegativeCacheTTL,
 	}
+	srv.config.Store(config)
+
+	return srv, nil
 }
 
 // Check implements the ext_authz Check RPC.
@@ -115,29 +247,56 @@ func (s *AuthorizationServer) Check(ctx context.Context, req *authv3.CheckReques
 		return s.denyResponse(codes.Unauthenticated, err.Error()), nil
 	}
 
-	// Validate token and get user info
-	user, orgs, err := s.validateTokenAndGetInfo(ctx, token)
+	// Validate token and get identity
+	authTypeHint := httpReq.GetHeaders()["x-auth-type"]
+
+	identity, err := s.validateTokenAndGetInfo(ctx, token, authTypeHint)
 	if err != nil {
 		s.logger.Warn("token validation failed", "error", err)
 		return s.denyResponse(codes.Unauthenticated, "invalid token: "+err.Error()), nil
 	}
 
 	// Check authorization rules
-	if err := s.checkAuthorization(user.Login, orgs); err != nil {
+	if err := s.checkAuthorization(ctx, token, identity); err != nil {
+		s.logger.Info("authorization denied",
+			"user", identity.Username,
+			"orgs", identity.Orgs,
+			"auth_method", identity.AuthMethod,
+			"reason", err.Error(),
+		)
+		return s.denyResponse(codes.PermissionDenied, err.Error()), nil
+	}
+
+	// Check the repo permission required of every request, then any
+	// per-path/method rule. Snapshot the config once so a concurrent policy
+	// reload can't mix RepoAllowList from one generation with
+	// RequiredRepoPermission from another.
+	cfg := s.cfg()
+	if err := s.checkRepoPermissions(ctx, token, identity, cfg.RepoAllowList, cfg.RequiredRepoPermission); err != nil {
+		s.logger.Info("authorization denied",
+			"user", identity.Username,
+			"auth_method", identity.AuthMethod,
+			"reason", err.Error(),
+		)
+		return s.denyResponse(codes.PermissionDenied, err.Error()), nil
+	}
+
+	if err := s.checkRules(ctx, token, identity, httpReq.GetPath(), httpReq.GetMethod()); err != nil {
 		s.logger.Info("authorization denied",
-			"user", user.Login,
-			"orgs", orgs,
+			"user", identity.Username,
+			"auth_method", identity.AuthMethod,
 			"reason", err.Error(),
 		)
 		return s.denyResponse(codes.PermissionDenied, err.Error()), nil
 	}
 
 	s.logger.Info("authorization granted",
-		"user", user.Login,
-		"orgs", orgs,
+		"user", identity.Username,
+		"orgs", identity.Orgs,
+		"auth_method", identity.AuthMethod,
 	)
 
-	return s.allowResponse(user, orgs), nil
+	return s.allowResponse(identity), nil
 }
 
 // extractBearerToken extracts the token from a "Bearer <token>" header value.
@@ -156,60 +315,133 @@ func extractBearerToken(authHeader string) (string, error) {
 	return token, nil
 }
 
-// validateTokenAndGetInfo validates the GitHub OAuth token and returns user information.
-func (s *AuthorizationServer) validateTokenAndGetInfo(ctx context.Context, token string) (*auth.GitHubUser, []string, error) {
-	// Check cache first
-	s.userCacheMu.RLock()
-	if cached, ok := s.userCache[token]; ok && time.Now().Before(cached.expiresAt) {
-		s.userCacheMu.RUnlock()
-		return cached.user, cached.orgs, nil
+// validateTokenAndGetInfo validates token against the appropriate
+// TokenValidator and returns the resulting identity.
+func (s *AuthorizationServer) validateTokenAndGetInfo(ctx context.Context, token, authTypeHint string) (*Identity, error) {
+	key := hashToken(token)
+
+	if entry, ok := s.cache.get(key); ok {
+		if entry.err != nil {
+			cacheNegativeHitsTotal.Inc()
+			return nil, entry.err
+		}
+
+		cacheHitsTotal.Inc()
+
+		identity, _ := entry.value.(*Identity)
+
+		return identity, nil
 	}
-	s.userCacheMu.RUnlock()
 
-	// Validate token by calling GitHub API
-	client := auth.NewGitHubClient(token)
+	cacheMissesTotal.Inc()
+
+	// Collapse concurrent validations of the same token into one upstream
+	// round trip; the first caller in does the work, the rest share its result.
+	result, err, shared := s.sf.Do(key, func() (interface{}, error) {
+		identity, verr := s.doValidateToken(ctx, token, authTypeHint)
+
+		ttl := s.userCacheTTL
+		if verr != nil {
+			ttl = s.negativeCacheTTL
+		}
+
+		s.cache.set(&cacheEntry{
+			key:       key,
+			value:     identity,
+			err:       verr,
+			expiresAt: time.Now().Add(ttl),
+		})
+
+		return identity, verr
+	})
+	if shared {
+		singleflightCollapsesTotal.Inc()
+	}
 
-	user, err := client.GetUser(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to validate token: %w", err)
+		return nil, err
 	}
 
-	orgs, err := client.GetOrgNames(ctx)
+	identity, _ := result.(*Identity)
+
+	return identity, nil
+}
+
+// doValidateToken selects the appropriate TokenValidator for token and runs it.
+func (s *AuthorizationServer) doValidateToken(ctx context.Context, token, authTypeHint string) (*Identity, error) {
+	validator, err := s.selectValidator(token, authTypeHint)
 	if err != nil {
-		s.logger.Warn("failed to fetch organizations", "user", user.Login, "error", err)
-		orgs = []string{} // Continue without org info
+		return nil, err
 	}
 
-	// Cache the result
-	s.userCacheMu.Lock()
-	s.userCache[token] = &cachedUser{
-		user:      user,
-		orgs:      orgs,
-		expiresAt: time.Now().Add(s.userCacheTTL),
+	identity, err := validator.Validate(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate token: %w", err)
 	}
-	s.userCacheMu.Unlock()
 
-	return user, orgs, nil
+	return identity, nil
+}
+
+// hashToken returns the hex-encoded SHA-256 digest of token, used as a cache
+// key so raw bearer tokens are never held in memory.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// selectValidator picks the TokenValidator for token, either from the
+// explicit "X-Auth-Type" header hint or, absent a hint, by the token's
+// shape: a JWT (three dot-separated base64 segments) is routed to the OIDC
+// validator when one is configured, anything else to the GitHub validator.
+func (s *AuthorizationServer) selectValidator(token, authTypeHint string) (TokenValidator, error) {
+	switch strings.ToLower(authTypeHint) {
+	case "oidc":
+		if s.oidcValidator == nil {
+			return nil, fmt.Errorf("OIDC validation requested but no OIDC issuer is configured")
+		}
+
+		return s.oidcValidator, nil
+	case "github":
+		return s.githubValidator, nil
+	case "":
+		if s.oidcValidator != nil && oidc.IsJWT(token) {
+			return s.oidcValidator, nil
+		}
+
+		return s.githubValidator, nil
+	default:
+		return nil, fmt.Errorf("unknown X-Auth-Type %q", authTypeHint)
+	}
 }
 
-// checkAuthorization checks if the user is authorized based on the configured rules.
-func (s *AuthorizationServer) checkAuthorization(username string, userOrgs []string) error {
+// checkAuthorization checks if the identity is authorized based on the
+// configured rules. token is only used for the GitHub-specific private
+// membership fallback and is ignored for non-GitHub identities.
+func (s *AuthorizationServer) checkAuthorization(ctx context.Context, token string, identity *Identity) error {
+	// Snapshot the config once so a concurrent policy reload can't apply part
+	// of the old policy and part of the new one to a single request.
+	cfg := s.cfg()
+
+	username := identity.Username
+	userOrgs := identity.Orgs
+	userTeams := identity.Teams
+
 	// Check deny list first (highest priority)
-	for _, denied := range s.config.UserDenyList {
+	for _, denied := range cfg.UserDenyList {
 		if strings.EqualFold(username, denied) {
 			return fmt.Errorf("user %q is in the deny list", username)
 		}
 	}
 
 	// Check user allow list (explicit allow)
-	for _, allowed := range s.config.UserAllowList {
+	for _, allowed := range cfg.UserAllowList {
 		if strings.EqualFold(username, allowed) {
 			return nil // Explicitly allowed
 		}
 	}
 
 	// If no organization restrictions, allow all authenticated users
-	if len(s.config.OrganizationAllowList) == 0 {
+	if len(cfg.OrganizationAllowList) == 0 {
 		return nil
 	}
 
@@ -219,28 +451,109 @@ func (s *AuthorizationServer) checkAuthorization(username string, userOrgs []str
 		userOrgSet[strings.ToLower(org)] = true
 	}
 
-	for _, allowedOrg := range s.config.OrganizationAllowList {
-		if userOrgSet[strings.ToLower(allowedOrg)] {
-			// User is member of an allowed org
-			// Check if team restrictions apply
-			if teams, hasTeamRestriction := s.config.TeamAllowList[allowedOrg]; hasTeamRestriction {
-				// TODO: Implement team membership check
-				// For now, if team restriction exists but we haven't fetched teams, allow
-				_ = teams
-				s.logger.Debug("team restriction configured but not checked (not implemented)",
+	for _, allowedOrg := range cfg.OrganizationAllowList {
+		isMember := userOrgSet[strings.ToLower(allowedOrg)]
+
+		// The user/orgs endpoint omits orgs where membership is private. Fall
+		// back to a direct membership check before giving up on this org. This
+		// only applies to GitHub identities; OIDC groups have no such fallback.
+		if !isMember && identity.AuthMethod == "github-oauth" {
+			member, err := s.isOrgMember(ctx, token, allowedOrg, username)
+			if err != nil {
+				s.logger.Debug("org membership fallback check failed",
 					"org", allowedOrg,
 					"user", username,
+					"error", err,
 				)
 			}
-			return nil // Allowed via org membership
+			isMember = member
 		}
+
+		if !isMember {
+			continue
+		}
+
+		// User is member of an allowed org; enforce team restrictions if configured.
+		if requiredTeams, hasTeamRestriction := cfg.TeamAllowList[allowedOrg]; hasTeamRestriction {
+			if !hasAnyTeamSlug(userTeams[allowedOrg], requiredTeams) {
+				return fmt.Errorf("user %q is not a member of any allowed team in organization %q", username, allowedOrg)
+			}
+		}
+
+		return nil // Allowed via org (and, if required, team) membership
 	}
 
 	return fmt.Errorf("user %q is not a member of any allowed organization", username)
 }
 
-// allowResponse creates an OK response with user information headers.
-func (s *AuthorizationServer) allowResponse(user *auth.GitHubUser, orgs []string) *authv3.CheckResponse {
+// isOrgMember checks whether username is a member of org via GitHub's
+// private-membership fallback endpoint, caching the result (positive or
+// negative) and collapsing concurrent lookups for the same token and org
+// into one upstream round trip, same as validateTokenAndGetInfo.
+func (s *AuthorizationServer) isOrgMember(ctx context.Context, token, org, username string) (bool, error) {
+	key := hashToken(token) + ":org:" + org
+
+	if entry, ok := s.orgMemberCache.get(key); ok {
+		if entry.err != nil {
+			cacheNegativeHitsTotal.Inc()
+			return false, entry.err
+		}
+
+		cacheHitsTotal.Inc()
+
+		member, _ := entry.value.(bool)
+
+		return member, nil
+	}
+
+	cacheMissesTotal.Inc()
+
+	result, err, shared := s.sf.Do(key, func() (interface{}, error) {
+		member, verr := auth.NewGitHubClient(token, s.githubOpts).IsOrgMember(ctx, org, username)
+
+		ttl := s.userCacheTTL
+		if verr != nil {
+			ttl = s.negativeCacheTTL
+		}
+
+		s.orgMemberCache.set(&cacheEntry{
+			key:       key,
+			value:     member,
+			err:       verr,
+			expiresAt: time.Now().Add(ttl),
+		})
+
+		return member, verr
+	})
+	if shared {
+		singleflightCollapsesTotal.Inc()
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	member, _ := result.(bool)
+
+	return member, nil
+}
+
+// hasAnyTeamSlug reports whether userTeamSlugs contains any of allowedSlugs,
+// compared case-insensitively.
+func hasAnyTeamSlug(userTeamSlugs, allowedSlugs []string) bool {
+	for _, allowed := range allowedSlugs {
+		for _, userTeam := range userTeamSlugs {
+			if strings.EqualFold(userTeam, allowed) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// allowResponse creates an OK response with identity information headers.
+func (s *AuthorizationServer) allowResponse(identity *Identity) *authv3.CheckResponse {
 	return &authv3.CheckResponse{
 		Status: &status.Status{Code: int32(codes.OK)},
 		HttpResponse: &authv3.CheckResponse_OkResponse{
@@ -249,25 +562,25 @@ func (s *AuthorizationServer) allowResponse(user *auth.GitHubUser, orgs []string
 					{
 						Header: &corev3.HeaderValue{
 							Key:   "x-github-user",
-							Value: user.Login,
+							Value: identity.Username,
 						},
 					},
 					{
 						Header: &corev3.HeaderValue{
 							Key:   "x-github-user-id",
-							Value: fmt.Sprintf("%d", user.ID),
+							Value: identity.Subject,
 						},
 					},
 					{
 						Header: &corev3.HeaderValue{
 							Key:   "x-github-orgs",
-							Value: strings.Join(orgs, ","),
+							Value: strings.Join(identity.Orgs, ","),
 						},
 					},
 					{
 						Header: &corev3.HeaderValue{
 							Key:   "x-auth-method",
-							Value: "github-oauth",
+							Value: identity.AuthMethod,
 						},
 					},
 				},
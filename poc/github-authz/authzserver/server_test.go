@@ -0,0 +1,121 @@
+package authzserver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingValidator is a TokenValidator that counts invocations and blocks
+// until release is closed, so concurrent callers overlap long enough for
+// singleflight to collapse them.
+type countingValidator struct {
+	calls   int32
+	release chan struct{}
+	err     error
+}
+
+func (v *countingValidator) Validate(ctx context.Context, token string) (*Identity, error) {
+	atomic.AddInt32(&v.calls, 1)
+
+	if v.release != nil {
+		<-v.release
+	}
+
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	return &Identity{Username: "octocat", AuthMethod: "github-oauth"}, nil
+}
+
+func newTestServer(validator TokenValidator, negativeCacheTTL time.Duration) *AuthorizationServer {
+	s := &AuthorizationServer{
+		githubValidator:  validator,
+		cache:            newLRUCache(100),
+		repoPermCache:    newLRUCache(100),
+		orgMemberCache:   newLRUCache(100),
+		userCacheTTL:     time.Minute,
+		negativeCacheTTL: negativeCacheTTL,
+	}
+	s.config.Store(DefaultConfig())
+
+	return s
+}
+
+func TestValidateTokenAndGetInfoSingleflightCollapse(t *testing.T) {
+	validator := &countingValidator{release: make(chan struct{})}
+	s := newTestServer(validator, time.Second)
+
+	const concurrency = 10
+
+	var wg sync.WaitGroup
+
+	wg.Add(concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+
+			if _, err := s.validateTokenAndGetInfo(context.Background(), "same-token", ""); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	// Give every goroutine a chance to reach the blocking Validate call
+	// before releasing it, so they all land in the same singleflight call.
+	time.Sleep(50 * time.Millisecond)
+	close(validator.release)
+
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&validator.calls); got != 1 {
+		t.Fatalf("validator was called %d times, want 1 (singleflight should have collapsed concurrent calls)", got)
+	}
+}
+
+func TestValidateTokenAndGetInfoCachesResult(t *testing.T) {
+	validator := &countingValidator{}
+	s := newTestServer(validator, time.Second)
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.validateTokenAndGetInfo(context.Background(), "same-token", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&validator.calls); got != 1 {
+		t.Fatalf("validator was called %d times, want 1 (subsequent calls should hit the cache)", got)
+	}
+}
+
+func TestValidateTokenAndGetInfoNegativeCacheExpires(t *testing.T) {
+	validator := &countingValidator{err: fmt.Errorf("invalid token")}
+	s := newTestServer(validator, 10*time.Millisecond)
+
+	if _, err := s.validateTokenAndGetInfo(context.Background(), "bad-token", ""); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if _, err := s.validateTokenAndGetInfo(context.Background(), "bad-token", ""); err == nil {
+		t.Fatal("expected the cached error to be returned")
+	}
+
+	if got := atomic.LoadInt32(&validator.calls); got != 1 {
+		t.Fatalf("validator was called %d times, want 1 (second call should hit the negative cache)", got)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := s.validateTokenAndGetInfo(context.Background(), "bad-token", ""); err == nil {
+		t.Fatal("expected an error after the negative cache entry expired")
+	}
+
+	if got := atomic.LoadInt32(&validator.calls); got != 2 {
+		t.Fatalf("validator was called %d times, want 2 (negative cache entry should have expired)", got)
+	}
+}
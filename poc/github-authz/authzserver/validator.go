@@ -0,0 +1,118 @@
+package authzserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/agntcy/dir-staging/poc/github-authz/auth"
+	"github.com/agntcy/dir-staging/poc/github-authz/oidc"
+)
+
+// Identity is the authenticated principal produced by a TokenValidator,
+// independent of whether the token came from GitHub OAuth or an OIDC
+// provider.
+type Identity struct {
+	// Username identifies the principal for logging, headers, and
+	// UserAllowList/UserDenyList checks.
+	Username string
+
+	// Subject is the provider-assigned principal identifier: a GitHub
+	// numeric user ID (as a string) or an OIDC "sub" claim.
+	Subject string
+
+	// Orgs are the organizations (GitHub orgs, or OIDC groups/roles standing
+	// in for them) the principal belongs to.
+	Orgs []string
+
+	// Teams maps org -> team slugs within that org. Only populated for the
+	// GitHub validator; OIDC identities have no notion of GitHub teams.
+	Teams map[string][]string
+
+	// AuthMethod identifies which validator produced this identity, e.g.
+	// "github-oauth" or "oidc".
+	AuthMethod string
+}
+
+// TokenValidator authenticates a bearer token and returns the identity it
+// represents.
+type TokenValidator interface {
+	Validate(ctx context.Context, token string) (*Identity, error)
+}
+
+// GitHubTokenValidator validates opaque GitHub OAuth/personal access tokens
+// by calling the GitHub API.
+type GitHubTokenValidator struct {
+	opts auth.Options
+}
+
+// NewGitHubTokenValidator creates a GitHubTokenValidator using opts to reach
+// GitHub.com or a GitHub Enterprise Server instance.
+func NewGitHubTokenValidator(opts auth.Options) *GitHubTokenValidator {
+	return &GitHubTokenValidator{opts: opts}
+}
+
+// Validate implements TokenValidator.
+func (v *GitHubTokenValidator) Validate(ctx context.Context, token string) (*Identity, error) {
+	client := auth.NewGitHubClient(token, v.opts)
+
+	user, err := client.GetUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate token: %w", err)
+	}
+
+	orgs, err := client.GetOrgNames(ctx)
+	if err != nil {
+		orgs = []string{} // Continue without org info
+	}
+
+	teams, err := client.GetTeamsByOrg(ctx)
+	if err != nil {
+		teams = make(map[string][]string) // Continue without team info
+	}
+
+	return &Identity{
+		Username:   user.Login,
+		Subject:    fmt.Sprintf("%d", user.ID),
+		Orgs:       orgs,
+		Teams:      teams,
+		AuthMethod: "github-oauth",
+	}, nil
+}
+
+// OIDCTokenValidator validates JWT bearer tokens issued by a configured OIDC
+// provider, treating a configurable groups/roles claim as the equivalent of
+// GitHub organization membership.
+type OIDCTokenValidator struct {
+	validator *oidc.Validator
+}
+
+// NewOIDCTokenValidator creates an OIDCTokenValidator for the given issuer,
+// audience, and groups claim (see oidc.Config).
+func NewOIDCTokenValidator(cfg oidc.Config) (*OIDCTokenValidator, error) {
+	validator, err := oidc.NewValidator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCTokenValidator{validator: validator}, nil
+}
+
+// Validate implements TokenValidator.
+func (v *OIDCTokenValidator) Validate(ctx context.Context, token string) (*Identity, error) {
+	claims, err := v.validator.Validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	username := claims.PreferredUsername
+	if username == "" {
+		username = claims.Subject
+	}
+
+	return &Identity{
+		Username:   username,
+		Subject:    claims.Subject,
+		Orgs:       claims.Groups,
+		AuthMethod: "oidc",
+	}, nil
+}
@@ -11,6 +11,9 @@
 //
 //	# With environment variables
 //	GITHUB_ALLOWED_ORGS=agntcy,spiffe AUTHZ_PORT=9001 go run ./cmd/github-authz-server
+//
+//	# With a hot-reloadable policy file
+//	go run ./cmd/github-authz-server --policy-file ./policy.yaml
 package main
 
 import (
@@ -19,13 +22,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
@@ -35,8 +41,9 @@ import (
 )
 
 const (
-	defaultPort     = "9001"
-	defaultCacheTTL = 5 * time.Minute
+	defaultPort        = "9001"
+	defaultMetricsPort = "9090"
+	defaultCacheTTL    = 5 * time.Minute
 )
 
 func main() {
@@ -64,10 +71,31 @@ func main() {
 		"allowed_users_count", len(config.UserAllowList),
 		"denied_users_count", len(config.UserDenyList),
 		"cache_ttl", config.CacheTTL,
+		"github_hostname", config.GitHubHostName,
+		"oidc_issuer", config.OIDCIssuer,
+		"allowed_repos", config.RepoAllowList,
+		"required_repo_permission", config.RequiredRepoPermission,
+		"policy_file", config.PolicyFilePath,
 	)
 
 	// Create authorization server
-	authzServer := authzserver.NewAuthorizationServer(config, logger)
+	authzServer, err := authzserver.NewAuthorizationServer(config, logger)
+	if err != nil {
+		logger.Error("failed to create authorization server", "error", err)
+		os.Exit(1)
+	}
+
+	// Handle graceful shutdown; also used to stop the policy file watcher.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if config.PolicyFilePath != "" {
+		go func() {
+			if err := authzServer.WatchPolicyFile(ctx); err != nil {
+				logger.Error("policy file watcher stopped", "error", err)
+			}
+		}()
+	}
 
 	// Create gRPC server
 	grpcServer := grpc.NewServer(
@@ -87,6 +115,19 @@ func main() {
 	// Enable reflection for debugging
 	reflection.Register(grpcServer)
 
+	// Serve Prometheus metrics (cache hit/miss/negative-hit and singleflight
+	// collapse counters) on a separate HTTP port.
+	metricsPort := getEnv("AUTHZ_METRICS_PORT", defaultMetricsPort)
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		logger.Info("metrics server listening", "port", metricsPort)
+		if err := http.ListenAndServe(":"+metricsPort, metricsMux); err != nil {
+			logger.Error("metrics server error", "error", err)
+		}
+	}()
+
 	// Start listening
 	port := getEnv("AUTHZ_PORT", defaultPort)
 	listener, err := net.Listen("tcp", ":"+port)
@@ -95,10 +136,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Handle graceful shutdown
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
 	// Start server in goroutine
 	go func() {
 		logger.Info("server listening", "address", listener.Addr().String())
@@ -154,6 +191,52 @@ func loadConfig() (*authzserver.Config, error) {
 		config.CacheTTL = duration
 	}
 
+	// Negative cache TTL (for failed token validations)
+	if ttl := os.Getenv("AUTHZ_NEGATIVE_CACHE_TTL"); ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTHZ_NEGATIVE_CACHE_TTL: %w", err)
+		}
+		config.NegativeCacheTTL = duration
+	}
+
+	// Maximum number of cached identities (LRU-evicted beyond this)
+	if max := os.Getenv("AUTHZ_MAX_CACHE_ENTRIES"); max != "" {
+		n, err := strconv.Atoi(max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTHZ_MAX_CACHE_ENTRIES: %w", err)
+		}
+		config.MaxCacheEntries = n
+	}
+
+	// GitHub Enterprise Server hostname, for self-hosted GitHub deployments.
+	config.GitHubHostName = os.Getenv("GITHUB_HOSTNAME")
+
+	// Custom CA bundle for GHES instances behind a private PKI.
+	config.RootCAPath = os.Getenv("GITHUB_ROOT_CA_PATH")
+
+	// OIDC/JWT bearer token validation, as an alternative to GitHub OAuth.
+	config.OIDCIssuer = os.Getenv("OIDC_ISSUER")
+	config.OIDCAudience = os.Getenv("OIDC_AUDIENCE")
+	config.OIDCGroupsClaim = os.Getenv("OIDC_GROUPS_CLAIM")
+
+	// Repository allow list and the minimum collaborator permission required on it.
+	if repos := os.Getenv("GITHUB_ALLOWED_REPOS"); repos != "" {
+		config.RepoAllowList = splitAndTrim(repos, ",")
+	}
+	config.RequiredRepoPermission = os.Getenv("GITHUB_REQUIRED_REPO_PERMISSION")
+
+	// Policy file: hot-reloadable organizations/teams/users/rules, overriding
+	// the allow-list env vars above. See --policy-file.
+	config.PolicyFilePath = getEnv("AUTHZ_POLICY_FILE", "")
+	for i, arg := range os.Args {
+		if arg == "--policy-file" && i+1 < len(os.Args) {
+			config.PolicyFilePath = os.Args[i+1]
+		} else if strings.HasPrefix(arg, "--policy-file=") {
+			config.PolicyFilePath = strings.TrimPrefix(arg, "--policy-file=")
+		}
+	}
+
 	return config, nil
 }
 
@@ -0,0 +1,441 @@
+// Package oidc validates OIDC/JWT bearer tokens issued by a configured
+// issuer, resolving its signing keys from the issuer's JWKS endpoint.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// discoveryTimeout bounds how long discovery/JWKS fetches are allowed to take.
+const discoveryTimeout = 10 * time.Second
+
+// minKeyRefreshInterval bounds how often refreshKeys will hit the issuer's
+// JWKS endpoint, regardless of how many lookups miss the key cache. Without
+// it, a token with an unknown "kid" (including an unsigned or otherwise
+// forged JWT, which is rejected only after this lookup) would force a live
+// JWKS round trip on every single request.
+const minKeyRefreshInterval = 10 * time.Second
+
+// Claims holds the subset of JWT claims the authorization server cares about.
+type Claims struct {
+	Subject           string
+	PreferredUsername string
+	Groups            []string
+}
+
+// Config configures a Validator.
+type Config struct {
+	// Issuer is the OIDC issuer URL (the "iss" claim tokens must carry).
+	// Its well-known discovery document is used to locate the JWKS endpoint.
+	Issuer string
+
+	// Audience is the expected "aud" claim value.
+	Audience string
+
+	// GroupsClaim is the name of the claim carrying group/role membership,
+	// e.g. "groups" or "roles". Defaults to "groups" if empty.
+	GroupsClaim string
+
+	// HTTPClient is used for discovery and JWKS fetches. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Validator verifies JWT bearer tokens issued by a single OIDC issuer.
+type Validator struct {
+	issuer      string
+	audience    string
+	groupsClaim string
+	httpClient  *http.Client
+
+	sf singleflight.Group
+
+	mu              sync.RWMutex
+	jwksURI         string
+	keys            map[string]*rsa.PublicKey
+	keysExpiresAt   time.Time
+	keysRefreshedAt time.Time
+}
+
+// NewValidator creates a Validator for the given configuration.
+func NewValidator(cfg Config) (*Validator, error) {
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("oidc: issuer must not be empty")
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	groupsClaim := cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	return &Validator{
+		issuer:      strings.TrimSuffix(cfg.Issuer, "/"),
+		audience:    cfg.Audience,
+		groupsClaim: groupsClaim,
+		httpClient:  httpClient,
+		keys:        make(map[string]*rsa.PublicKey),
+	}, nil
+}
+
+// IsJWT reports whether token looks like a JWT: three dot-separated
+// base64url segments. It does not validate the token's contents.
+func IsJWT(token string) bool {
+	return len(strings.Split(token, ".")) == 3
+}
+
+// Validate verifies token's signature, issuer, audience, and expiry/not-before
+// window, and returns the claims an authorization decision needs.
+func (v *Validator) Validate(ctx context.Context, token string) (*Claims, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, fmt.Errorf("oidc: not a JWT")
+	}
+
+	header, err := decodeJSONSegment(segments[0])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid header: %w", err)
+	}
+
+	kid, _ := header["kid"].(string)
+	alg, _ := header["alg"].(string)
+	if alg != "RS256" {
+		return nil, fmt.Errorf("oidc: unsupported signing algorithm %q", alg)
+	}
+
+	claims, err := decodeJSONSegment(segments[1])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid claims: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid signature encoding: %w", err)
+	}
+
+	key, err := v.signingKey(ctx, kid)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: failed to resolve signing key: %w", err)
+	}
+
+	signedInput := segments[0] + "." + segments[1]
+	digest := sha256.Sum256([]byte(signedInput))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("oidc: signature verification failed: %w", err)
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claimsFromMap(claims, v.groupsClaim), nil
+}
+
+// validateClaims checks the "iss", "sub", "aud", "exp", and "nbf" claims.
+// "iss", "sub", and "exp" are mandatory: a token missing any of them is
+// rejected rather than treated as unrestricted.
+func (v *Validator) validateClaims(claims map[string]interface{}) error {
+	if iss, _ := claims["iss"].(string); iss != v.issuer {
+		return fmt.Errorf("oidc: unexpected issuer %q", iss)
+	}
+
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return fmt.Errorf("oidc: token is missing the \"sub\" claim")
+	}
+
+	if v.audience != "" && !audienceMatches(claims["aud"], v.audience) {
+		return fmt.Errorf("oidc: token audience does not include %q", v.audience)
+	}
+
+	exp, ok := numericClaim(claims["exp"])
+	if !ok {
+		return fmt.Errorf("oidc: token is missing the \"exp\" claim")
+	}
+
+	now := time.Now()
+
+	if now.After(time.Unix(exp, 0)) {
+		return fmt.Errorf("oidc: token has expired")
+	}
+
+	if nbf, ok := numericClaim(claims["nbf"]); ok && now.Before(time.Unix(nbf, 0)) {
+		return fmt.Errorf("oidc: token is not yet valid")
+	}
+
+	return nil
+}
+
+// audienceMatches reports whether aud (a string or []interface{} per the
+// JWT spec) contains want.
+func audienceMatches(aud interface{}, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// numericClaim coerces a JSON-decoded numeric claim (always float64) to an
+// int64 Unix timestamp.
+func numericClaim(v interface{}) (int64, bool) {
+	f, ok := v.(float64)
+	if !ok {
+		return 0, false
+	}
+
+	return int64(f), true
+}
+
+// claimsFromMap extracts the claims this package cares about from the
+// decoded claim set.
+func claimsFromMap(claims map[string]interface{}, groupsClaim string) *Claims {
+	out := &Claims{}
+
+	if sub, ok := claims["sub"].(string); ok {
+		out.Subject = sub
+	}
+
+	if username, ok := claims["preferred_username"].(string); ok {
+		out.PreferredUsername = username
+	}
+
+	switch groups := claims[groupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				out.Groups = append(out.Groups, s)
+			}
+		}
+	case string:
+		out.Groups = []string{groups}
+	}
+
+	return out
+}
+
+// decodeJSONSegment base64url-decodes and JSON-unmarshals a JWT segment.
+func decodeJSONSegment(segment string) (map[string]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// jwk is a single JSON Web Key as returned by a JWKS endpoint.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// jwks is a JSON Web Key Set document.
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// signingKey returns the RSA public key for kid, fetching and caching the
+// issuer's JWKS document as needed.
+func (v *Validator) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	fresh := ok && time.Now().Before(v.keysExpiresAt)
+	v.mu.RUnlock()
+
+	if fresh {
+		return key, nil
+	}
+
+	if err := v.refreshKeysRateLimited(ctx); err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	key, ok = v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no signing key found for kid %q", kid)
+	}
+
+	return key, nil
+}
+
+// refreshKeysRateLimited refreshes the cached signing keys, collapsing
+// concurrent callers into a single upstream fetch and skipping the fetch
+// entirely if one already completed within minKeyRefreshInterval. A kid
+// that's merely unknown (rather than actually stale) would otherwise drive
+// one refreshKeys call per lookup.
+func (v *Validator) refreshKeysRateLimited(ctx context.Context) error {
+	v.mu.RLock()
+	tooSoon := time.Since(v.keysRefreshedAt) < minKeyRefreshInterval
+	v.mu.RUnlock()
+
+	if tooSoon {
+		return nil
+	}
+
+	_, err, _ := v.sf.Do("refresh", func() (interface{}, error) {
+		// Record the attempt before making it, so a failing upstream (not
+		// just a successful refresh) is also rate-limited.
+		v.mu.Lock()
+		v.keysRefreshedAt = time.Now()
+		v.mu.Unlock()
+
+		return nil, v.refreshKeys(ctx)
+	})
+
+	return err
+}
+
+// refreshKeys discovers the JWKS endpoint (if not already known) and
+// refreshes the cached signing keys.
+func (v *Validator) refreshKeys(ctx context.Context) error {
+	jwksURI, err := v.jwksEndpoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned %s", resp.Status)
+	}
+
+	var doc jwks
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		pubKey, err := k.rsaPublicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = pubKey
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.keysExpiresAt = time.Now().Add(discoveryTimeout * 6) // ~1 minute cache
+	v.mu.Unlock()
+
+	return nil
+}
+
+// jwksEndpoint returns the issuer's JWKS URI, discovering it from the
+// issuer's well-known OpenID configuration document on first use.
+func (v *Validator) jwksEndpoint(ctx context.Context) (string, error) {
+	v.mu.RLock()
+	uri := v.jwksURI
+	v.mu.RUnlock()
+
+	if uri != "" {
+		return uri, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	discoveryURL := v.issuer + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC discovery endpoint returned %s", resp.Status)
+	}
+
+	var doc struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to parse OIDC discovery document: %w", err)
+	}
+
+	if doc.JWKSURI == "" {
+		return "", fmt.Errorf("OIDC discovery document is missing jwks_uri")
+	}
+
+	v.mu.Lock()
+	v.jwksURI = doc.JWKSURI
+	v.mu.Unlock()
+
+	return doc.JWKSURI, nil
+}
+
+// rsaPublicKey decodes the key's base64url-encoded modulus/exponent into an
+// *rsa.PublicKey.
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
@@ -0,0 +1,251 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestIssuer starts an httptest.Server serving OIDC discovery and JWKS
+// documents for key, under kid.
+func newTestIssuer(t *testing.T, key *rsa.PrivateKey, kid string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	var issuerURL string
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"jwks_uri": issuerURL + "/jwks.json",
+		})
+	})
+
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwks{
+			Keys: []jwk{
+				{
+					Kty: "RSA",
+					Kid: kid,
+					N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+					E:   base64.RawURLEncoding.EncodeToString(big64(key.PublicKey.E)),
+				},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	issuerURL = srv.URL
+
+	return srv
+}
+
+// big64 encodes a small int exponent as minimal big-endian bytes.
+func big64(e int) []byte {
+	if e == 0 {
+		return []byte{0}
+	}
+
+	var b []byte
+	for e > 0 {
+		b = append([]byte{byte(e & 0xff)}, b...)
+		e >>= 8
+	}
+
+	return b
+}
+
+// signToken builds and RS256-signs a JWT from the given claims.
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]interface{}{"alg": "RS256", "typ": "JWT", "kid": kid}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+
+	signedInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(signedInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return signedInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestValidatorValidate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	const kid = "test-key"
+
+	issuer := newTestIssuer(t, key, kid)
+	defer issuer.Close()
+
+	baseClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": issuer.URL,
+			"sub": "user-123",
+			"aud": "authz-server",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		claims    map[string]interface{}
+		wantError bool
+	}{
+		{
+			name:   "valid token",
+			claims: baseClaims(),
+		},
+		{
+			name: "missing exp",
+			claims: func() map[string]interface{} {
+				c := baseClaims()
+				delete(c, "exp")
+				return c
+			}(),
+			wantError: true,
+		},
+		{
+			name: "missing sub",
+			claims: func() map[string]interface{} {
+				c := baseClaims()
+				delete(c, "sub")
+				return c
+			}(),
+			wantError: true,
+		},
+		{
+			name: "expired",
+			claims: func() map[string]interface{} {
+				c := baseClaims()
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+				return c
+			}(),
+			wantError: true,
+		},
+		{
+			name: "not yet valid",
+			claims: func() map[string]interface{} {
+				c := baseClaims()
+				c["nbf"] = time.Now().Add(time.Hour).Unix()
+				return c
+			}(),
+			wantError: true,
+		},
+		{
+			name: "wrong issuer",
+			claims: func() map[string]interface{} {
+				c := baseClaims()
+				c["iss"] = "https://not-the-issuer.example"
+				return c
+			}(),
+			wantError: true,
+		},
+		{
+			name: "wrong audience",
+			claims: func() map[string]interface{} {
+				c := baseClaims()
+				c["aud"] = "someone-else"
+				return c
+			}(),
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator, err := NewValidator(Config{
+				Issuer:     issuer.URL,
+				Audience:   "authz-server",
+				HTTPClient: issuer.Client(),
+			})
+			if err != nil {
+				t.Fatalf("NewValidator: %v", err)
+			}
+
+			token := signToken(t, key, kid, tt.claims)
+
+			_, err = validator.Validate(context.Background(), token)
+			if tt.wantError && err == nil {
+				t.Fatalf("expected an error, got none")
+			}
+			if !tt.wantError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatorValidateBadSignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	const kid = "test-key"
+
+	issuer := newTestIssuer(t, key, kid)
+	defer issuer.Close()
+
+	validator, err := NewValidator(Config{Issuer: issuer.URL, HTTPClient: issuer.Client()})
+	if err != nil {
+		t.Fatalf("NewValidator: %v", err)
+	}
+
+	token := signToken(t, otherKey, kid, map[string]interface{}{
+		"iss": issuer.URL,
+		"sub": "user-123",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	if _, err := validator.Validate(context.Background(), token); err == nil {
+		t.Fatal("expected signature verification to fail")
+	}
+}
+
+func TestIsJWT(t *testing.T) {
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{"a.b.c", true},
+		{"ghp_abc123", false},
+		{"a.b", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsJWT(tt.token); got != tt.want {
+			t.Errorf("IsJWT(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}